@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// imageCountBuckets bounds a histogram of "images per repository" so
+// accounts running anywhere from a handful to a few thousand tags per repo
+// still land in a useful bucket.
+var imageCountBuckets = prometheus.ExponentialBuckets(1, 4, 8) // 1 .. 16384
+
+// imageSizeBuckets bounds a histogram of image sizes, in bytes, from 1MiB up
+// to 16GiB, the rough range of real container images.
+var imageSizeBuckets = prometheus.ExponentialBuckets(1<<20, 4, 8) // 1MiB .. 16GiB
+
+// collectAggregateRepositoryMetrics is the --metrics.per-repo=false
+// alternative to collectRepositoryMetrics: instead of a repository_name
+// labeled gauge per repo, it emits fleet-wide histograms so the exported
+// cardinality stays constant regardless of how many repositories a target
+// has.
+func collectAggregateRepositoryMetrics(ctx context.Context, client *ecr.Client, descs *metricDescs, target, region, account string, repos []types.Repository, ch chan<- prometheus.Metric, errorCount *int) {
+	labels := []string{target, region, account}
+
+	imageCounts := make([]float64, 0, len(repos))
+	var imageSizes []float64
+
+	for _, repo := range repos {
+		if repo.RepositoryName == nil {
+			continue
+		}
+
+		images, err := getRepositoryImages(ctx, client, *repo.RepositoryName)
+		if err != nil {
+			log.Error("Failed to get images for repository", "repository", *repo.RepositoryName, "error", err)
+			*errorCount++
+			continue
+		}
+
+		imageCounts = append(imageCounts, float64(len(images)))
+		for _, image := range images {
+			if image.ImageSizeInBytes != nil {
+				imageSizes = append(imageSizes, float64(*image.ImageSizeInBytes))
+			}
+		}
+	}
+
+	ch <- histogramMetric(descs.aggImageCount, imageCountBuckets, imageCounts, labels)
+	ch <- histogramMetric(descs.aggImageSize, imageSizeBuckets, imageSizes, labels)
+}
+
+// histogramMetric builds a ConstHistogram over values bucketed by bounds.
+// Every bound gets an entry, including zero counts, since
+// NewConstHistogram expects cumulative counts for each configured bucket.
+func histogramMetric(desc *prometheus.Desc, bounds []float64, values []float64, labelValues []string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(bounds))
+	for _, bound := range bounds {
+		buckets[bound] = 0
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+		for _, bound := range bounds {
+			if v <= bound {
+				buckets[bound]++
+			}
+		}
+	}
+
+	return prometheus.MustNewConstHistogram(desc, uint64(len(values)), sum, buckets, labelValues...)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestHistogramMetric(t *testing.T) {
+	desc := prometheus.NewDesc("test_hist", "test histogram", nil, nil)
+	metric := histogramMetric(desc, []float64{1, 10, 100}, []float64{1, 5, 50}, nil)
+
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("Unexpected error writing metric: %v", err)
+	}
+
+	h := m.GetHistogram()
+	if h.GetSampleCount() != 3 {
+		t.Errorf("Expected sample count 3, got %d", h.GetSampleCount())
+	}
+	if h.GetSampleSum() != 56 {
+		t.Errorf("Expected sample sum 56, got %v", h.GetSampleSum())
+	}
+	if len(h.GetBucket()) != 3 {
+		t.Errorf("Expected 3 buckets, got %d", len(h.GetBucket()))
+	}
+}
+
+func TestHistogramMetricEmptyValues(t *testing.T) {
+	desc := prometheus.NewDesc("test_hist_empty", "test histogram", nil, nil)
+	metric := histogramMetric(desc, []float64{1, 10}, nil, nil)
+
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("Unexpected error writing metric: %v", err)
+	}
+
+	h := m.GetHistogram()
+	if h.GetSampleCount() != 0 {
+		t.Errorf("Expected sample count 0, got %d", h.GetSampleCount())
+	}
+	for _, b := range h.GetBucket() {
+		if b.GetCumulativeCount() != 0 {
+			t.Errorf("Expected zero-filled buckets when no values, got %d", b.GetCumulativeCount())
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultCacheTTL is how often the background refresh loop re-scrapes ECR
+// when the exporter isn't told otherwise.
+const defaultCacheTTL = 5 * time.Minute
+
+// metricsCache holds the most recently scraped metrics plus bookkeeping
+// about that scrape, so Collect() can serve Prometheus instantly instead of
+// blocking on DescribeRepositories/DescribeImages calls that can run for
+// minutes on accounts with many repositories - longer than Prometheus's
+// default 10s scrape timeout.
+type metricsCache struct {
+	mu sync.RWMutex
+
+	metrics        []prometheus.Metric
+	lastRefresh    time.Time
+	refreshErrors  int64
+	lastRefreshDur time.Duration
+}
+
+func (c *metricsCache) snapshot() (metrics []prometheus.Metric, lastRefresh time.Time, refreshErrors int64, refreshDur time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.metrics, c.lastRefresh, c.refreshErrors, c.lastRefreshDur
+}
+
+func (c *metricsCache) store(metrics []prometheus.Metric, refreshDur time.Duration, failed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics = metrics
+	c.lastRefresh = time.Now()
+	c.lastRefreshDur = refreshDur
+	if failed {
+		c.refreshErrors++
+	}
+}
+
+// refresh runs a full, synchronous scrape across every configured target
+// and stores the result in the cache. It's what both the background refresh
+// loop and the /-/refresh endpoint call.
+func (c *ECRCollector) refresh() {
+	start := time.Now()
+	targets := c.snapshotClients()
+
+	log.Info("Refreshing ECR metrics cache", "targets", len(targets))
+
+	ch := make(chan prometheus.Metric, 256)
+	var metrics []prometheus.Metric
+	done := make(chan struct{})
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+
+	var totalErrors int64
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, c.concurrency)
+
+	for _, tc := range targets {
+		wg.Add(1)
+		go func(tc *targetClient) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			errs := c.collectTarget(tc, ch)
+
+			mu.Lock()
+			totalErrors += int64(errs)
+			mu.Unlock()
+		}(tc)
+	}
+	wg.Wait()
+	close(ch)
+	<-done
+
+	duration := time.Since(start)
+	metrics = append(metrics,
+		prometheus.MustNewConstMetric(c.descs.scrapeErrors, prometheus.CounterValue, float64(totalErrors)),
+		prometheus.MustNewConstMetric(c.descs.scrapeDuration, prometheus.GaugeValue, duration.Seconds()),
+	)
+	c.cache.store(metrics, duration, totalErrors > 0)
+	logDedupe.Flush(context.Background())
+
+	log.Info("Cache refresh completed", "duration_seconds", duration.Seconds(), "errors", totalErrors, "targets", len(targets))
+}
+
+// runCacheRefreshLoop refreshes the cache once immediately and then on
+// every tick of ttl, for as long as the process runs; nothing stops the loop.
+func (c *ECRCollector) runCacheRefreshLoop(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	c.refresh()
+
+	ticker := time.NewTicker(ttl)
+	defer ticker.Stop()
+	for range ticker.C {
+		c.refresh()
+	}
+}
+
+// refreshHandler backs POST /-/refresh, forcing an immediate cache refresh
+// instead of waiting for the next scheduled tick.
+func refreshHandler(collector *ECRCollector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		collector.refresh()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("cache refreshed\n"))
+	}
+}
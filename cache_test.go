@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetricsCacheStoreAndSnapshot(t *testing.T) {
+	cache := &metricsCache{}
+
+	metrics, lastRefresh, refreshErrors, refreshDur := cache.snapshot()
+	if metrics != nil {
+		t.Error("Expected nil metrics before the first store")
+	}
+	if !lastRefresh.IsZero() {
+		t.Error("Expected zero lastRefresh before the first store")
+	}
+	if refreshErrors != 0 {
+		t.Error("Expected zero refreshErrors before the first store")
+	}
+	if refreshDur != 0 {
+		t.Error("Expected zero refreshDur before the first store")
+	}
+
+	desc := prometheus.NewDesc("test_metric", "a test metric", nil, nil)
+	stored := []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)}
+
+	cache.store(stored, 2*time.Second, false)
+	metrics, lastRefresh, refreshErrors, refreshDur = cache.snapshot()
+	if len(metrics) != 1 {
+		t.Fatalf("Expected 1 metric, got %d", len(metrics))
+	}
+	if lastRefresh.IsZero() {
+		t.Error("Expected non-zero lastRefresh after a store")
+	}
+	if refreshErrors != 0 {
+		t.Errorf("Expected 0 refreshErrors, got %d", refreshErrors)
+	}
+	if refreshDur != 2*time.Second {
+		t.Errorf("Expected refreshDur 2s, got %s", refreshDur)
+	}
+
+	cache.store(stored, time.Second, true)
+	_, _, refreshErrors, _ = cache.snapshot()
+	if refreshErrors != 1 {
+		t.Errorf("Expected refreshErrors to increment to 1, got %d", refreshErrors)
+	}
+}
+
+func TestRefreshHandlerMethodNotAllowed(t *testing.T) {
+	collector := newECRCollector(nil, 0, nil)
+	handler := refreshHandler(collector)
+
+	req := httptest.NewRequest("GET", "/-/refresh", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+// TestRefreshHandlerRefreshesEmptyCache exercises ECRCollector.refresh through
+// POST /-/refresh; refresh calls logDedupe.Flush at the end of every refresh.
+// For the duration of the test logDedupe is replaced by a real dedupeHandler
+// writing to a buffer rather than os.Stdout. The package default that
+// refresh() sees before configureLogging has run is covered by
+// TestCollectorRefreshBeforeConfigureLogging below.
+func TestRefreshHandlerRefreshesEmptyCache(t *testing.T) {
+	var buf bytes.Buffer
+	origDedupe, origLog := logDedupe, log
+	logDedupe = &dedupeHandler{next: slog.NewTextHandler(&buf, nil)}
+	log = slog.New(logDedupe)
+	defer func() { logDedupe, log = origDedupe, origLog }()
+
+	collector := newECRCollector(nil, 0, nil)
+	handler := refreshHandler(collector)
+
+	req := httptest.NewRequest("POST", "/-/refresh", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	_, lastRefresh, _, _ := collector.cache.snapshot()
+	if lastRefresh.IsZero() {
+		t.Error("Expected lastRefresh to be set after POST /-/refresh")
+	}
+}
+
+// TestCollectorRefreshBeforeConfigureLogging pins down the actual nil-panic
+// hazard this test file used to hit: ECRCollector.refresh calling
+// logDedupe.Flush when logDedupe still has its package-level zero-config
+// default, because configureLogging (which only runs from main()) never
+// ran. logDedupe must never be nil for this to be safe.
+func TestCollectorRefreshBeforeConfigureLogging(t *testing.T) {
+	if logDedupe == nil {
+		t.Fatal("Expected logDedupe to have a non-nil default so refresh() is safe before configureLogging runs")
+	}
+
+	collector := newECRCollector(nil, 0, nil)
+	collector.refresh()
+
+	_, lastRefresh, _, _ := collector.cache.snapshot()
+	if lastRefresh.IsZero() {
+		t.Error("Expected lastRefresh to be set after refresh()")
+	}
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
@@ -10,168 +12,183 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// defaultConcurrency bounds how many targets are scraped in parallel when
+// the exporter isn't told otherwise.
+const defaultConcurrency = 4
+
+// ECRCollector scrapes one or more AWS accounts/regions, as described by the
+// targets it's constructed (or reloaded) with, and exposes their
+// repositories as Prometheus metrics. Each cache refresh fans the targets
+// out across a bounded worker pool so a slow or failing account doesn't
+// block the others; Collect() itself only serves the cached result.
 type ECRCollector struct {
-	client *ecr.Client
-
-	// Metrics
-	repoCount          *prometheus.Desc
-	imageCount         *prometheus.Desc
-	imageSizeMax       *prometheus.Desc
-	imageSizeMin       *prometheus.Desc
-	imageSizeAvg       *prometheus.Desc
-	latestPushTime     *prometheus.Desc
-	latestPullTime     *prometheus.Desc
-	scrapeErrors       *prometheus.Desc
-	scrapeDuration     *prometheus.Desc
+	mu          sync.RWMutex
+	clients     map[string]*targetClient
+	concurrency int
+
+	descs     *metricDescs
+	cache     *metricsCache
+	scanCache *scanFindingsCache
+	scanSem   chan struct{}
+	filter    *repoFilter
+}
+
+// NewECRCollector builds a collector for the given targets. concurrency is
+// the maximum number of targets scraped at once; values <= 0 fall back to
+// defaultConcurrency.
+func NewECRCollector(ctx context.Context, cfg *TargetsConfig, concurrency int, filter *repoFilter) (*ECRCollector, error) {
+	clients, err := buildTargetClients(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newECRCollector(clients, concurrency, filter), nil
 }
 
-func NewECRCollector(client *ecr.Client) *ECRCollector {
+// newECRCollector builds a collector around an already-constructed client
+// map, without making any AWS calls itself. It's split out from
+// NewECRCollector so tests can exercise the Describe/Collect machinery
+// without real credentials.
+func newECRCollector(clients map[string]*targetClient, concurrency int, filter *repoFilter) *ECRCollector {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
 	return &ECRCollector{
-		client: client,
-		repoCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "repositories_total"),
-			"Total number of ECR repositories",
-			nil,
-			nil,
-		),
-		imageCount: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "images_total"),
-			"Number of images in ECR repository",
-			[]string{"repository_name", "repository_uri"},
-			nil,
-		),
-		imageSizeMax: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "image_size_max_bytes"),
-			"Maximum image size in repository (bytes)",
-			[]string{"repository_name", "repository_uri"},
-			nil,
-		),
-		imageSizeMin: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "image_size_min_bytes"),
-			"Minimum image size in repository (bytes)",
-			[]string{"repository_name", "repository_uri"},
-			nil,
-		),
-		imageSizeAvg: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "image_size_avg_bytes"),
-			"Average image size in repository (bytes)",
-			[]string{"repository_name", "repository_uri"},
-			nil,
-		),
-		latestPushTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "latest_push_timestamp"),
-			"Timestamp of latest image push",
-			[]string{"repository_name", "repository_uri"},
-			nil,
-		),
-		latestPullTime: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "latest_pull_timestamp"),
-			"Timestamp of latest image pull",
-			[]string{"repository_name", "repository_uri"},
-			nil,
-		),
-		scrapeErrors: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "scrape_errors_total"),
-			"Total number of scrape errors",
-			nil,
-			nil,
-		),
-		scrapeDuration: prometheus.NewDesc(
-			prometheus.BuildFQName(namespace, "", "scrape_duration_seconds"),
-			"Duration of the scrape",
-			nil,
-			nil,
-		),
+		clients:     clients,
+		concurrency: concurrency,
+		descs:       newMetricDescs(),
+		cache:       &metricsCache{},
+		scanCache:   newScanFindingsCache(*scanCacheSize),
+		scanSem:     make(chan struct{}, *scanConcurrency),
+		filter:      filter,
+	}
+}
+
+// SetClients atomically replaces the collector's target/client map. It's
+// used to hot-reload the config file without restarting the exporter.
+func (c *ECRCollector) SetClients(clients map[string]*targetClient) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clients = clients
+}
+
+// targetByName returns the client for the named target, or an error if no
+// such target is configured.
+func (c *ECRCollector) targetByName(name string) (*targetClient, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	tc, ok := c.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown target %q", name)
+	}
+	return tc, nil
+}
+
+// targetByRegion returns the client for whichever configured target scrapes
+// the given region. It's used by /probe, which addresses targets by region
+// rather than by the target's config name.
+func (c *ECRCollector) targetByRegion(region string) (*targetClient, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, tc := range c.clients {
+		if tc.target.Region == region {
+			return tc, nil
+		}
+	}
+	return nil, fmt.Errorf("no configured target scrapes region %q", region)
+}
+
+func (c *ECRCollector) snapshotClients() []*targetClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := make([]*targetClient, 0, len(c.clients))
+	for _, tc := range c.clients {
+		snapshot = append(snapshot, tc)
 	}
+	return snapshot
 }
 
 func (c *ECRCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- c.repoCount
-	ch <- c.imageCount
-	ch <- c.imageSizeMax
-	ch <- c.imageSizeMin
-	ch <- c.imageSizeAvg
-	ch <- c.latestPushTime
-	ch <- c.latestPullTime
-	ch <- c.scrapeErrors
-	ch <- c.scrapeDuration
+	for _, d := range c.descs.all() {
+		ch <- d
+	}
 }
 
+// Collect serves metrics from the in-memory cache rather than calling ECR
+// synchronously, so a Prometheus scrape never blocks on DescribeRepositories
+// or DescribeImages. The cache itself is kept warm by runCacheRefreshLoop
+// (or manually via POST /-/refresh).
 func (c *ECRCollector) Collect(ch chan<- prometheus.Metric) {
+	metrics, lastRefresh, refreshErrors, refreshDur := c.cache.snapshot()
+
+	for _, m := range metrics {
+		ch <- m
+	}
+
+	if !lastRefresh.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.descs.cacheLastRefresh, prometheus.GaugeValue, float64(lastRefresh.Unix()))
+	}
+	ch <- prometheus.MustNewConstMetric(c.descs.cacheRefreshDur, prometheus.GaugeValue, refreshDur.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.descs.cacheRefreshErrors, prometheus.CounterValue, float64(refreshErrors))
+}
+
+// collectTarget scrapes a single target end to end and returns the number
+// of errors encountered, so a failure in one account/region never prevents
+// the others' metrics from being sent.
+func (c *ECRCollector) collectTarget(tc *targetClient, ch chan<- prometheus.Metric) int {
 	start := time.Now()
 	errorCount := 0
+	name := tc.target.Name
 
-	log.Info("Starting metrics collection")
+	log.Info("Fetching ECR repositories", "target", name)
 
-	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	// Get all repositories
-	log.Info("Fetching ECR repositories...")
-	repos, err := c.getAllRepositories(ctx)
+	repos, err := getAllRepositories(ctx, tc.client)
 	if err != nil {
-		log.Errorf("Failed to get repositories: %v", err)
+		log.Error("Failed to get repositories", "target", name, "error", err)
 		errorCount++
-		// Still send error metrics even if we can't get repos
-		ch <- prometheus.MustNewConstMetric(
-			c.repoCount,
-			prometheus.GaugeValue,
-			0,
-		)
+		ch <- prometheus.MustNewConstMetric(c.descs.repoCount, prometheus.GaugeValue, 0, name, tc.target.Region, tc.accountID)
 	} else {
-		log.Infof("Found %d repositories", len(repos))
-		// Send total repository count
-		ch <- prometheus.MustNewConstMetric(
-			c.repoCount,
-			prometheus.GaugeValue,
-			float64(len(repos)),
-		)
-
-		// Process each repository
-		for i, repo := range repos {
-			log.Infof("Processing repository %d/%d: %s", i+1, len(repos), *repo.RepositoryName)
-			c.collectRepositoryMetrics(ctx, repo, ch, &errorCount)
-			log.Infof("Completed repository %d/%d: %s", i+1, len(repos), *repo.RepositoryName)
+		repos = filterRepositories(ctx, tc.client, c.filter, repos, &errorCount)
+
+		log.Info("Found repositories", "target", name, "count", len(repos))
+		ch <- prometheus.MustNewConstMetric(c.descs.repoCount, prometheus.GaugeValue, float64(len(repos)), name, tc.target.Region, tc.accountID)
+
+		if *perRepoMetrics {
+			for _, repo := range repos {
+				collectRepositoryMetrics(ctx, tc.client, c.descs, c.scanCache, c.scanSem, tc.target.Region, tc.accountID, repo, ch, &errorCount)
+			}
+		} else {
+			collectAggregateRepositoryMetrics(ctx, tc.client, c.descs, name, tc.target.Region, tc.accountID, repos, ch, &errorCount)
 		}
 	}
 
-	log.Info("Sending final scrape metrics...")
-	// Send scrape metrics
-	ch <- prometheus.MustNewConstMetric(
-		c.scrapeErrors,
-		prometheus.CounterValue,
-		float64(errorCount),
-	)
-
-	ch <- prometheus.MustNewConstMetric(
-		c.scrapeDuration,
-		prometheus.GaugeValue,
-		time.Since(start).Seconds(),
-	)
-
-	log.Infof("Metrics collection completed in %.2f seconds with %d errors", time.Since(start).Seconds(), errorCount)
+	ch <- prometheus.MustNewConstMetric(c.descs.targetScrapeErrors, prometheus.CounterValue, float64(errorCount), name)
+	ch <- prometheus.MustNewConstMetric(c.descs.targetScrapeDur, prometheus.GaugeValue, time.Since(start).Seconds(), name)
+
+	return errorCount
 }
 
-func (c *ECRCollector) getAllRepositories(ctx context.Context) ([]types.Repository, error) {
+func getAllRepositories(ctx context.Context, client *ecr.Client) ([]types.Repository, error) {
 	var allRepos []types.Repository
 	var nextToken *string
 
-	log.Debug("Starting to fetch repositories")
-	
 	for {
 		input := &ecr.DescribeRepositoriesInput{
 			NextToken: nextToken,
 		}
 
-		log.Debug("Making DescribeRepositories API call")
-		result, err := c.client.DescribeRepositories(ctx, input)
+		result, err := client.DescribeRepositories(ctx, input)
 		if err != nil {
-			log.Errorf("DescribeRepositories API call failed: %v", err)
 			return nil, err
 		}
 
-		log.Debugf("Got %d repositories in this batch", len(result.Repositories))
 		allRepos = append(allRepos, result.Repositories...)
 
 		if result.NextToken == nil {
@@ -180,66 +197,70 @@ func (c *ECRCollector) getAllRepositories(ctx context.Context) ([]types.Reposito
 		nextToken = result.NextToken
 	}
 
-	log.Debugf("Total repositories fetched: %d", len(allRepos))
 	return allRepos, nil
 }
 
-func (c *ECRCollector) collectRepositoryMetrics(ctx context.Context, repo types.Repository, ch chan<- prometheus.Metric, errorCount *int) {
-	// Add nil checks
+// collectRepositoryByName looks up a single repository by name and collects
+// its metrics, without requiring a prior call to getAllRepositories. It
+// backs the /probe endpoint, where Prometheus asks for exactly one
+// repository at a time rather than the whole account.
+func collectRepositoryByName(ctx context.Context, client *ecr.Client, descs *metricDescs, scanCache *scanFindingsCache, scanSem chan struct{}, repoName, region, account string, ch chan<- prometheus.Metric) error {
+	result, err := client.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{repoName},
+	})
+	if err != nil {
+		return fmt.Errorf("describing repository %s: %w", repoName, err)
+	}
+	if len(result.Repositories) == 0 {
+		return fmt.Errorf("repository %s not found", repoName)
+	}
+
+	errorCount := 0
+	collectRepositoryMetrics(ctx, client, descs, scanCache, scanSem, region, account, result.Repositories[0], ch, &errorCount)
+	if errorCount > 0 {
+		return fmt.Errorf("collecting metrics for repository %s produced %d error(s)", repoName, errorCount)
+	}
+	return nil
+}
+
+// collectRepositoryMetrics fetches images for repo and emits all per-repo
+// metrics for it. It's a standalone function (rather than an ECRCollector
+// method) so both the all-targets cache refresh path and the single-repo
+// /probe path can call it against whichever client and metric descs apply.
+func collectRepositoryMetrics(ctx context.Context, client *ecr.Client, descs *metricDescs, scanCache *scanFindingsCache, scanSem chan struct{}, region, account string, repo types.Repository, ch chan<- prometheus.Metric, errorCount *int) {
 	if repo.RepositoryName == nil {
 		log.Error("Repository name is nil, skipping")
 		*errorCount++
 		return
 	}
 	if repo.RepositoryUri == nil {
-		log.Errorf("Repository URI is nil for repo %s, skipping", *repo.RepositoryName)
+		log.Error("Repository URI is nil, skipping", "repository", *repo.RepositoryName)
 		*errorCount++
 		return
 	}
 
 	repoName := *repo.RepositoryName
 	repoURI := *repo.RepositoryUri
+	labels := []string{repoName, repoURI, region, account}
 
-	log.Debugf("Starting collectRepositoryMetrics for: %s", repoName)
-
-	labels := []string{repoName, repoURI}
-
-	log.Debugf("Fetching images for repository: %s", repoName)
-	// Get images for this repository
-	images, err := c.getRepositoryImages(ctx, repoName)
+	images, err := getRepositoryImages(ctx, client, repoName)
 	if err != nil {
-		log.Errorf("Failed to get images for repository %s: %v", repoName, err)
+		log.Error("Failed to get images for repository", "repository", repoName, "error", err)
 		*errorCount++
-		// Still send zero count for this repo
-		log.Debugf("Sending zero image count metric for repository: %s", repoName)
-		ch <- prometheus.MustNewConstMetric(
-			c.imageCount,
-			prometheus.GaugeValue,
-			0,
-			labels...,
-		)
-		log.Debugf("Finished processing repository %s (with error)", repoName)
+		ch <- prometheus.MustNewConstMetric(descs.imageCount, prometheus.GaugeValue, 0, labels...)
 		return
 	}
 
-	log.Debugf("Found %d images in repository %s", len(images), repoName)
-
-	// Image count
-	log.Debugf("Sending image count metric for repository: %s", repoName)
-	ch <- prometheus.MustNewConstMetric(
-		c.imageCount,
-		prometheus.GaugeValue,
-		float64(len(images)),
-		labels...,
-	)
-	log.Debugf("Image count metric sent for repository: %s", repoName)
+	ch <- prometheus.MustNewConstMetric(descs.imageCount, prometheus.GaugeValue, float64(len(images)), labels...)
 
 	if len(images) == 0 {
-		log.Debugf("No images found, finishing repository: %s", repoName)
 		return
 	}
 
-	// Calculate size metrics
+	if *collectScanFindings {
+		collectScanFindingsForRepo(ctx, client, descs, scanCache, scanSem, repoName, images, ch, errorCount)
+	}
+
 	var sizes []int64
 	var latestPush, latestPull time.Time
 
@@ -257,7 +278,6 @@ func (c *ECRCollector) collectRepositoryMetrics(ctx context.Context, repo types.
 		}
 	}
 
-	// Size statistics
 	if len(sizes) > 0 {
 		sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
 
@@ -270,71 +290,35 @@ func (c *ECRCollector) collectRepositoryMetrics(ctx context.Context, repo types.
 		}
 		avgSize := float64(totalSize) / float64(len(sizes))
 
-		ch <- prometheus.MustNewConstMetric(
-			c.imageSizeMin,
-			prometheus.GaugeValue,
-			minSize,
-			labels...,
-		)
-
-		ch <- prometheus.MustNewConstMetric(
-			c.imageSizeMax,
-			prometheus.GaugeValue,
-			maxSize,
-			labels...,
-		)
-
-		ch <- prometheus.MustNewConstMetric(
-			c.imageSizeAvg,
-			prometheus.GaugeValue,
-			avgSize,
-			labels...,
-		)
+		ch <- prometheus.MustNewConstMetric(descs.imageSizeMin, prometheus.GaugeValue, minSize, labels...)
+		ch <- prometheus.MustNewConstMetric(descs.imageSizeMax, prometheus.GaugeValue, maxSize, labels...)
+		ch <- prometheus.MustNewConstMetric(descs.imageSizeAvg, prometheus.GaugeValue, avgSize, labels...)
 	}
 
-	// Latest push time
 	if !latestPush.IsZero() {
-		ch <- prometheus.MustNewConstMetric(
-			c.latestPushTime,
-			prometheus.GaugeValue,
-			float64(latestPush.Unix()),
-			labels...,
-		)
+		ch <- prometheus.MustNewConstMetric(descs.latestPushTime, prometheus.GaugeValue, float64(latestPush.Unix()), labels...)
 	}
 
-	// Latest pull time
 	if !latestPull.IsZero() {
-		ch <- prometheus.MustNewConstMetric(
-			c.latestPullTime,
-			prometheus.GaugeValue,
-			float64(latestPull.Unix()),
-			labels...,
-		)
+		ch <- prometheus.MustNewConstMetric(descs.latestPullTime, prometheus.GaugeValue, float64(latestPull.Unix()), labels...)
 	}
-
-	log.Debugf("Finished processing repository: %s", repoName)
 }
 
-func (c *ECRCollector) getRepositoryImages(ctx context.Context, repoName string) ([]types.ImageDetail, error) {
+func getRepositoryImages(ctx context.Context, client *ecr.Client, repoName string) ([]types.ImageDetail, error) {
 	var allImages []types.ImageDetail
 	var nextToken *string
 
-	log.Debugf("Starting to fetch images for repository: %s", repoName)
-	
 	for {
 		input := &ecr.DescribeImagesInput{
 			RepositoryName: &repoName,
 			NextToken:      nextToken,
 		}
 
-		log.Debugf("Making DescribeImages API call for repository: %s", repoName)
-		result, err := c.client.DescribeImages(ctx, input)
+		result, err := client.DescribeImages(ctx, input)
 		if err != nil {
-			log.Errorf("DescribeImages API call failed for repository %s: %v", repoName, err)
 			return nil, err
 		}
 
-		log.Debugf("Got %d images in this batch for repository: %s", len(result.ImageDetails), repoName)
 		allImages = append(allImages, result.ImageDetails...)
 
 		if result.NextToken == nil {
@@ -343,6 +327,5 @@ func (c *ECRCollector) getRepositoryImages(ctx context.Context, repoName string)
 		nextToken = result.NextToken
 	}
 
-	log.Debugf("Total images fetched for repository %s: %d", repoName, len(allImages))
 	return allImages, nil
-}
\ No newline at end of file
+}
@@ -9,21 +9,25 @@ import (
 )
 
 func TestNewECRCollector(t *testing.T) {
-	collector := NewECRCollector(nil)
+	collector := newECRCollector(nil, 0, nil)
 
 	if collector == nil {
 		t.Fatal("Expected non-nil collector")
 	}
 
-	if collector.client != nil {
-		t.Error("Expected nil client when passed nil")
+	if collector.clients != nil {
+		t.Error("Expected nil clients map when passed nil")
+	}
+
+	if collector.concurrency != defaultConcurrency {
+		t.Errorf("Expected concurrency to fall back to %d, got %d", defaultConcurrency, collector.concurrency)
 	}
 }
 
 func TestECRCollectorDescribe(t *testing.T) {
-	collector := NewECRCollector(nil)
+	collector := newECRCollector(nil, 0, nil)
 
-	ch := make(chan *prometheus.Desc, 10)
+	ch := make(chan *prometheus.Desc, 19)
 	go func() {
 		collector.Describe(ch)
 		close(ch)
@@ -34,7 +38,7 @@ func TestECRCollectorDescribe(t *testing.T) {
 		descs = append(descs, desc)
 	}
 
-	expectedCount := 9 // Number of metrics we export
+	expectedCount := 19 // Number of metrics we export
 	if len(descs) != expectedCount {
 		t.Errorf("Expected %d metric descriptions, got %d", expectedCount, len(descs))
 	}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"gopkg.in/yaml.v3"
+)
+
+// Target describes a single AWS account/region combination the exporter
+// should scrape ECR from. RoleARN is optional; when empty the target is
+// scraped using the exporter's default credential chain.
+type Target struct {
+	Name       string `yaml:"name"`
+	Region     string `yaml:"region"`
+	RoleARN    string `yaml:"role_arn"`
+	ExternalID string `yaml:"external_id"`
+}
+
+// TargetsConfig is the top-level shape of the YAML file passed via
+// --config.file.
+type TargetsConfig struct {
+	Targets []Target `yaml:"targets"`
+}
+
+// loadTargetsConfig reads and validates the YAML config file at path.
+func loadTargetsConfig(path string) (*TargetsConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	var cfg TargetsConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("config file %s defines no targets", path)
+	}
+
+	seen := make(map[string]bool, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		if t.Name == "" {
+			return nil, fmt.Errorf("target with region %q: name is required", t.Region)
+		}
+		if t.Region == "" {
+			return nil, fmt.Errorf("target %q: region is required", t.Name)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("target %q: duplicate name", t.Name)
+		}
+		seen[t.Name] = true
+	}
+
+	return &cfg, nil
+}
+
+// targetClient pairs a Target with the ecr.Client built for it and the AWS
+// account ID it resolves to, so per-repository metrics can be labeled with
+// both region and account without a round trip on every scrape. stsClient is
+// kept around (rather than discarded after the startup account-ID lookup)
+// so the /readyz credential checkable can re-probe it later.
+type targetClient struct {
+	target    Target
+	client    *ecr.Client
+	stsClient *sts.Client
+	accountID string
+}
+
+// buildTargetClient loads AWS config for t.Region and, when t.RoleARN is
+// set, assumes that role via STS before constructing the ecr.Client. It also
+// resolves the caller's account ID once so it can be attached as a label.
+func buildTargetClient(ctx context.Context, t Target) (*targetClient, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(t.Region))
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config for target %q: %w", t.Name, err)
+	}
+
+	if t.RoleARN != "" {
+		stsClient := sts.NewFromConfig(cfg)
+		provider := stscreds.NewAssumeRoleProvider(stsClient, t.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			if t.ExternalID != "" {
+				o.ExternalID = aws.String(t.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	stsClient := sts.NewFromConfig(cfg)
+	identity, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("resolving account ID for target %q: %w", t.Name, err)
+	}
+
+	return &targetClient{
+		target:    t,
+		client:    ecr.NewFromConfig(cfg),
+		stsClient: stsClient,
+		accountID: aws.ToString(identity.Account),
+	}, nil
+}
+
+// buildTargetClients builds a targetClient for every target in cfg. It
+// returns an error on the first target that fails to build rather than
+// producing a partial map, since a bad config entry should fail the reload
+// (or startup) instead of silently scraping fewer accounts than configured.
+func buildTargetClients(ctx context.Context, cfg *TargetsConfig) (map[string]*targetClient, error) {
+	clients := make(map[string]*targetClient, len(cfg.Targets))
+	for _, t := range cfg.Targets {
+		tc, err := buildTargetClient(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		clients[t.Name] = tc
+	}
+	return clients, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTempConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "targets.yml")
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("Failed to write temp config: %v", err)
+	}
+	return path
+}
+
+func TestLoadTargetsConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		contents  string
+		wantErr   bool
+		wantCount int
+	}{
+		{
+			name: "valid single target",
+			contents: `
+targets:
+  - name: prod
+    region: us-east-1
+`,
+			wantCount: 1,
+		},
+		{
+			name: "valid multi target with role",
+			contents: `
+targets:
+  - name: prod
+    region: us-east-1
+    role_arn: arn:aws:iam::111111111111:role/ecr-exporter
+    external_id: prod-ext-id
+  - name: staging
+    region: eu-west-1
+`,
+			wantCount: 2,
+		},
+		{
+			name:     "no targets",
+			contents: `targets: []`,
+			wantErr:  true,
+		},
+		{
+			name: "missing name",
+			contents: `
+targets:
+  - region: us-east-1
+`,
+			wantErr: true,
+		},
+		{
+			name: "missing region",
+			contents: `
+targets:
+  - name: prod
+`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate name",
+			contents: `
+targets:
+  - name: prod
+    region: us-east-1
+  - name: prod
+    region: eu-west-1
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTempConfig(t, tt.contents)
+
+			cfg, err := loadTargetsConfig(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected an error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(cfg.Targets) != tt.wantCount {
+				t.Errorf("Expected %d targets, got %d", tt.wantCount, len(cfg.Targets))
+			}
+		})
+	}
+}
+
+func TestLoadTargetsConfigMissingFile(t *testing.T) {
+	_, err := loadTargetsConfig(filepath.Join(t.TempDir(), "does-not-exist.yml"))
+	if err == nil {
+		t.Fatal("Expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+var (
+	repoInclude     = flag.String("repo.include", "", "Regex of repository names to scrape. If set, repositories not matching are skipped.")
+	repoExclude     = flag.String("repo.exclude", "", "Regex of repository names to skip.")
+	repoTagSelector = flag.String("repo.tag-selector", "", "Only scrape repositories tagged key=value (checked via ListTagsForResource).")
+	perRepoMetrics  = flag.Bool("metrics.per-repo", true, "Emit per-repository metrics (repository_name/repository_uri labels). Set to false on large accounts to emit only fleet-wide aggregates instead.")
+)
+
+// repoFilter holds the compiled --repo.include/--repo.exclude/--repo.tag-selector
+// flags. A nil *repoFilter (used by tests that don't go through compileRepoFilter)
+// matches every repository.
+type repoFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+	tagKey  string
+	tagVal  string
+}
+
+// compileRepoFilter parses the repository filtering flags once at startup, so
+// a bad regex or malformed tag selector fails fast instead of erroring on
+// every scrape.
+func compileRepoFilter() (*repoFilter, error) {
+	f := &repoFilter{}
+
+	if *repoInclude != "" {
+		re, err := regexp.Compile(*repoInclude)
+		if err != nil {
+			return nil, fmt.Errorf("compiling --repo.include: %w", err)
+		}
+		f.include = re
+	}
+
+	if *repoExclude != "" {
+		re, err := regexp.Compile(*repoExclude)
+		if err != nil {
+			return nil, fmt.Errorf("compiling --repo.exclude: %w", err)
+		}
+		f.exclude = re
+	}
+
+	if *repoTagSelector != "" {
+		key, val, ok := strings.Cut(*repoTagSelector, "=")
+		if !ok {
+			return nil, fmt.Errorf("--repo.tag-selector must be in key=value form, got %q", *repoTagSelector)
+		}
+		f.tagKey = key
+		f.tagVal = val
+	}
+
+	return f, nil
+}
+
+// matchesName reports whether repoName passes the include/exclude regexes.
+func (f *repoFilter) matchesName(repoName string) bool {
+	if f == nil {
+		return true
+	}
+	if f.include != nil && !f.include.MatchString(repoName) {
+		return false
+	}
+	if f.exclude != nil && f.exclude.MatchString(repoName) {
+		return false
+	}
+	return true
+}
+
+// matchesTags reports whether repo carries the configured --repo.tag-selector
+// tag, fetching its tags via ListTagsForResource. It always returns true when
+// no tag selector is configured, without making an API call.
+func (f *repoFilter) matchesTags(ctx context.Context, client *ecr.Client, repo types.Repository) (bool, error) {
+	if f == nil || f.tagKey == "" {
+		return true, nil
+	}
+	if repo.RepositoryArn == nil {
+		return false, nil
+	}
+
+	result, err := client.ListTagsForResource(ctx, &ecr.ListTagsForResourceInput{
+		ResourceArn: repo.RepositoryArn,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, tag := range result.Tags {
+		if tag.Key != nil && *tag.Key == f.tagKey && tag.Value != nil && *tag.Value == f.tagVal {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// filterRepositories returns the subset of repos that pass f's name and tag
+// filters. Tag lookups only happen when a tag selector is configured, so
+// filtering by name alone never costs an extra API call per repository. A
+// repo whose tag lookup fails (e.g. transient ECR throttling) is logged and
+// skipped rather than aborting the whole pass, mirroring the errorCount/skip
+// convention collectRepositoryMetrics uses, so one bad tag call doesn't
+// discard every repo already matched earlier in the loop.
+func filterRepositories(ctx context.Context, client *ecr.Client, f *repoFilter, repos []types.Repository, errorCount *int) []types.Repository {
+	if f == nil {
+		return repos
+	}
+
+	filtered := make([]types.Repository, 0, len(repos))
+	for _, repo := range repos {
+		if repo.RepositoryName == nil || !f.matchesName(*repo.RepositoryName) {
+			continue
+		}
+
+		ok, err := f.matchesTags(ctx, client, repo)
+		if err != nil {
+			log.Error("Failed to check tags for repository", "repository", *repo.RepositoryName, "error", err)
+			*errorCount++
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		filtered = append(filtered, repo)
+	}
+	return filtered
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func TestRepoFilterMatchesName(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *repoFilter
+		repo    string
+		matches bool
+	}{
+		{"nil filter matches everything", nil, "anything", true},
+		{"empty filter matches everything", &repoFilter{}, "anything", true},
+		{"include matches", &repoFilter{include: regexp.MustCompile("^prod-")}, "prod-api", true},
+		{"include excludes non-match", &repoFilter{include: regexp.MustCompile("^prod-")}, "dev-api", false},
+		{"exclude rejects match", &repoFilter{exclude: regexp.MustCompile("-test$")}, "api-test", false},
+		{"exclude allows non-match", &repoFilter{exclude: regexp.MustCompile("-test$")}, "api-prod", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matchesName(tt.repo); got != tt.matches {
+				t.Errorf("matchesName(%q) = %v, want %v", tt.repo, got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestCompileRepoFilterInvalidTagSelector(t *testing.T) {
+	orig := *repoTagSelector
+	defer func() { *repoTagSelector = orig }()
+
+	*repoTagSelector = "not-a-key-value-pair"
+	if _, err := compileRepoFilter(); err == nil {
+		t.Error("Expected error for malformed --repo.tag-selector")
+	}
+}
+
+func TestCompileRepoFilterInvalidRegex(t *testing.T) {
+	orig := *repoInclude
+	defer func() { *repoInclude = orig }()
+
+	*repoInclude = "(unterminated"
+	if _, err := compileRepoFilter(); err == nil {
+		t.Error("Expected error for malformed --repo.include")
+	}
+}
+
+func TestFilterRepositoriesNilFilterPassthrough(t *testing.T) {
+	name := "some-repo"
+	repos := []types.Repository{{RepositoryName: &name}}
+
+	var errorCount int
+	filtered := filterRepositories(nil, nil, nil, repos, &errorCount)
+	if errorCount != 0 {
+		t.Errorf("Expected 0 errors, got %d", errorCount)
+	}
+	if len(filtered) != 1 {
+		t.Errorf("Expected nil filter to pass through all repos, got %d", len(filtered))
+	}
+}
+
+func TestFilterRepositoriesByName(t *testing.T) {
+	keep, drop := "prod-api", "dev-api"
+	repos := []types.Repository{
+		{RepositoryName: &keep},
+		{RepositoryName: &drop},
+	}
+
+	filter := &repoFilter{include: regexp.MustCompile("^prod-")}
+	var errorCount int
+	filtered := filterRepositories(nil, nil, filter, repos, &errorCount)
+	if errorCount != 0 {
+		t.Errorf("Expected 0 errors, got %d", errorCount)
+	}
+	if len(filtered) != 1 || *filtered[0].RepositoryName != keep {
+		t.Errorf("Expected only %q to remain, got %v", keep, filtered)
+	}
+}
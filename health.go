@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// checkTimeout bounds how long a single Checkable gets to report in before
+// /readyz counts it as failed, so one hung dependency can't hang the whole
+// readiness probe.
+const checkTimeout = 5 * time.Second
+
+// Checkable is a single health dependency the exporter can probe, following
+// the coreos/pkg health.Checkable pattern: a name for the JSON report, and a
+// Healthy call that returns nil when the dependency is up.
+type Checkable interface {
+	Name() string
+	Healthy(ctx context.Context) error
+}
+
+// checkResult is one Checkable's outcome, as reported in /readyz.
+type checkResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// readyzResponse is the full body of /readyz.
+type readyzResponse struct {
+	Status string        `json:"status"`
+	Checks []checkResult `json:"checks"`
+}
+
+// runChecks runs every checkable concurrently, each bounded by checkTimeout,
+// and aggregates the results. Checks are fanned out across a bounded worker
+// pool, the same defaultConcurrency used to scrape targets, so a hung
+// dependency only costs checkTimeout once instead of serializing the whole
+// /readyz response behind every other check. The overall status is
+// unhealthy if any one check fails.
+func runChecks(ctx context.Context, checks []Checkable) readyzResponse {
+	results := make([]checkResult, len(checks))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, defaultConcurrency)
+
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c Checkable) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			start := time.Now()
+			err := c.Healthy(cctx)
+			cancel()
+
+			result := checkResult{
+				Name:      c.Name(),
+				LatencyMS: time.Since(start).Milliseconds(),
+				Status:    "healthy",
+			}
+			if err != nil {
+				result.Status = "unhealthy"
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}(i, c)
+	}
+	wg.Wait()
+
+	status := "healthy"
+	for _, r := range results {
+		if r.Status != "healthy" {
+			status = "unhealthy"
+			break
+		}
+	}
+	return readyzResponse{Status: status, Checks: results}
+}
+
+// runtimeStatsCheckable adds a "runtime" entry to the /readyz aggregate. It
+// reports no runtime stats of its own and is always healthy as long as the
+// process is alive to run it.
+type runtimeStatsCheckable struct{}
+
+func (runtimeStatsCheckable) Name() string { return "runtime" }
+
+func (runtimeStatsCheckable) Healthy(ctx context.Context) error { return nil }
+
+// ecrReachabilityCheckable probes whether a target's ECR API is reachable
+// via the cheapest read-only call available, DescribeRegistry.
+type ecrReachabilityCheckable struct {
+	targetName string
+	client     *ecr.Client
+}
+
+func (c ecrReachabilityCheckable) Name() string { return "ecr:" + c.targetName }
+
+func (c ecrReachabilityCheckable) Healthy(ctx context.Context) error {
+	_, err := c.client.DescribeRegistry(ctx, &ecr.DescribeRegistryInput{})
+	return err
+}
+
+// stsCredentialCheckable probes whether a target's AWS credentials (and any
+// assumed role) are still valid via STS GetCallerIdentity.
+type stsCredentialCheckable struct {
+	targetName string
+	client     *sts.Client
+}
+
+func (c stsCredentialCheckable) Name() string { return "sts:" + c.targetName }
+
+func (c stsCredentialCheckable) Healthy(ctx context.Context) error {
+	_, err := c.client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	return err
+}
+
+// Checkables returns the collector's current health checks, rebuilt from its
+// live client map on every call so a hot-reload is reflected in the very
+// next /readyz.
+func (c *ECRCollector) Checkables() []Checkable {
+	clients := c.snapshotClients()
+	checks := make([]Checkable, 0, len(clients)*2+1)
+	checks = append(checks, runtimeStatsCheckable{})
+
+	for _, tc := range clients {
+		checks = append(checks, ecrReachabilityCheckable{targetName: tc.target.Name, client: tc.client})
+		checks = append(checks, stsCredentialCheckable{targetName: tc.target.Name, client: tc.stsClient})
+	}
+
+	return checks
+}
+
+// healthzHandler is a pure liveness probe: if the process can run this
+// handler at all, it's 200. It never touches ECR or AWS credentials, unlike
+// /readyz.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// readyzHandler runs every Checkable checks returns and reports 503 if any
+// of them failed, so a load balancer or Kubernetes readiness probe can take
+// the exporter out of rotation while its ECR/AWS dependencies are
+// unreachable. It takes a func() []Checkable rather than an *ECRCollector
+// directly so tests can drive it with a fixed, failing checkable list
+// without a real collector.
+func readyzHandler(checks func() []Checkable) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := runChecks(r.Context(), checks())
+
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status != "healthy" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeCheckable struct {
+	name string
+	err  error
+}
+
+func (f fakeCheckable) Name() string { return f.name }
+
+func (f fakeCheckable) Healthy(ctx context.Context) error { return f.err }
+
+func TestRunChecksAllHealthy(t *testing.T) {
+	checks := []Checkable{fakeCheckable{name: "a"}, fakeCheckable{name: "b"}}
+
+	resp := runChecks(context.Background(), checks)
+
+	if resp.Status != "healthy" {
+		t.Errorf("Expected overall status healthy, got %s", resp.Status)
+	}
+	if len(resp.Checks) != 2 {
+		t.Fatalf("Expected 2 check results, got %d", len(resp.Checks))
+	}
+	for _, c := range resp.Checks {
+		if c.Status != "healthy" || c.Error != "" {
+			t.Errorf("Expected check %s to be healthy with no error, got %+v", c.Name, c)
+		}
+	}
+}
+
+func TestRunChecksOneFailing(t *testing.T) {
+	checks := []Checkable{
+		fakeCheckable{name: "ok"},
+		fakeCheckable{name: "broken", err: errors.New("connection refused")},
+	}
+
+	resp := runChecks(context.Background(), checks)
+
+	if resp.Status != "unhealthy" {
+		t.Errorf("Expected overall status unhealthy, got %s", resp.Status)
+	}
+
+	var broken checkResult
+	for _, c := range resp.Checks {
+		if c.Name == "broken" {
+			broken = c
+		}
+	}
+	if broken.Status != "unhealthy" || broken.Error != "connection refused" {
+		t.Errorf("Expected broken check to report its error, got %+v", broken)
+	}
+}
+
+func TestHealthzAlwaysOK(t *testing.T) {
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	w := httptest.NewRecorder()
+	healthzHandler(w, req)
+
+	if w.Code != 200 {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+}
+
+func TestReadyzHandlerAllHealthy(t *testing.T) {
+	checks := func() []Checkable { return []Checkable{fakeCheckable{name: "runtime"}} }
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	readyzHandler(checks)(w, req)
+
+	if w.Code != 200 {
+		t.Errorf("Expected status 200 with no failing checks, got %d", w.Code)
+	}
+
+	var resp readyzResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Status != "healthy" {
+		t.Errorf("Expected healthy status, got %s", resp.Status)
+	}
+	if len(resp.Checks) != 1 || resp.Checks[0].Name != "runtime" {
+		t.Errorf("Expected a single runtime check, got %+v", resp.Checks)
+	}
+}
+
+// TestReadyzHandlerReflectsFailingCheckable is the actual "failing checkable
+// returns 503" case the request asked for: with one Checkable reporting an
+// error, /readyz must report 503 while /healthz (a pure liveness probe that
+// never runs any Checkable) stays 200.
+func TestReadyzHandlerReflectsFailingCheckable(t *testing.T) {
+	checks := func() []Checkable {
+		return []Checkable{
+			fakeCheckable{name: "ecr:prod"},
+			fakeCheckable{name: "sts:prod", err: errors.New("ExpiredToken")},
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	readyzHandler(checks)(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d from /readyz with a failing checkable, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	var resp readyzResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Status != "unhealthy" {
+		t.Errorf("Expected unhealthy status, got %s", resp.Status)
+	}
+
+	healthzReq := httptest.NewRequest("GET", "/healthz", nil)
+	healthzW := httptest.NewRecorder()
+	healthzHandler(healthzW, healthzReq)
+
+	if healthzW.Code != http.StatusOK {
+		t.Errorf("Expected /healthz to stay 200 regardless of /readyz's checks, got %d", healthzW.Code)
+	}
+}
+
+func TestRunChecksJSONSchema(t *testing.T) {
+	checks := []Checkable{fakeCheckable{name: "dep", err: errors.New("boom")}}
+	resp := runChecks(context.Background(), checks)
+
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if parsed["status"] != "unhealthy" {
+		t.Errorf("Expected status field 'unhealthy', got %v", parsed["status"])
+	}
+
+	checksField, ok := parsed["checks"].([]interface{})
+	if !ok || len(checksField) != 1 {
+		t.Fatalf("Expected checks array with 1 entry, got %v", parsed["checks"])
+	}
+
+	entry, ok := checksField[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected check entry to be an object, got %v", checksField[0])
+	}
+	for _, field := range []string{"name", "status", "error", "latency_ms"} {
+		if _, ok := entry[field]; !ok {
+			t.Errorf("Expected field %q in check entry, got %v", field, entry)
+		}
+	}
+}
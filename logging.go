@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logLevel is the process-wide slog level. It's a *slog.LevelVar rather
+// than a plain slog.Level so it can be adjusted at runtime (see the
+// log-level control endpoint) without rebuilding the logger.
+var logLevel = &slog.LevelVar{}
+
+// logDedupe is the dedupeHandler wrapping whatever format handler
+// configureLogging builds, kept at package scope so callers on a scrape's
+// hot path can flush a pending "repeated N times" summary at a natural
+// boundary (see ECRCollector.refresh), and so the log-level endpoint can swap
+// its format handler at runtime (see setLogFormat). It's given a safe
+// default here, mirroring how `log` defaults to slog.Default(), so a
+// refresh triggered before configureLogging runs (e.g. a test calling
+// ECRCollector.refresh directly) doesn't dereference a nil handler;
+// configureLogging replaces it with the LOG_LEVEL/LOG_FORMAT-configured one.
+var logDedupe = &dedupeHandler{next: slog.NewTextHandler(os.Stdout, nil)}
+
+// logFormatMu guards currentLogFormat, the raw LOG_FORMAT value last applied,
+// so the /admin/loglevel GET handler can report it without racing a
+// concurrent format change.
+var (
+	logFormatMu      sync.Mutex
+	currentLogFormat string
+)
+
+// configureLogging builds the global slog logger from the LOG_LEVEL and
+// LOG_FORMAT environment variables. This replaces the project's previous
+// use of sirupsen/logrus with the stdlib log/slog package, following the
+// same path the Prometheus project itself took off go-kit/log.
+func configureLogging() {
+	levelStr := strings.ToLower(os.Getenv("LOG_LEVEL"))
+	level, valid := parseLogLevel(levelStr)
+	logLevel.Set(level)
+
+	format := strings.ToLower(os.Getenv("LOG_FORMAT"))
+	logFormatMu.Lock()
+	currentLogFormat = format
+	logFormatMu.Unlock()
+	logDedupe = &dedupeHandler{next: newLogHandler(format, logLevel)}
+	log = slog.New(logDedupe)
+
+	if !valid && levelStr != "" {
+		log.Warn("invalid LOG_LEVEL, defaulting to info", "log_level", levelStr, "valid_levels", "debug, info, warn, error")
+	}
+
+	log.Info("Logging configured", "log_level", level.String(), "format", logFormatName(format))
+}
+
+// validLogFormat reports whether format is one setLogFormat will accept.
+func validLogFormat(format string) bool {
+	return format == "json" || format == "text" || format == "logfmt"
+}
+
+// setLogFormat swaps logDedupe's underlying handler to match format, without
+// replacing the global *slog.Logger or losing the current log level. It's
+// used by the /admin/loglevel endpoint to flip output format at runtime.
+func setLogFormat(format string) {
+	logFormatMu.Lock()
+	currentLogFormat = format
+	logFormatMu.Unlock()
+
+	logDedupe.setNext(newLogHandler(format, logLevel))
+}
+
+// getLogFormat returns the raw format string last applied via LOG_FORMAT or
+// setLogFormat.
+func getLogFormat() string {
+	logFormatMu.Lock()
+	defer logFormatMu.Unlock()
+	return currentLogFormat
+}
+
+// parseLogLevel maps a LOG_LEVEL value onto a slog.Level. ok is false for
+// anything unrecognized, in which case the caller should fall back to info.
+func parseLogLevel(s string) (level slog.Level, ok bool) {
+	switch s {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info", "":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	default:
+		return slog.LevelInfo, false
+	}
+}
+
+func logFormatName(format string) string {
+	if format == "json" {
+		return "json"
+	}
+	return "logfmt"
+}
+
+// newLogHandler builds the slog.Handler matching LOG_FORMAT: "json" for
+// slog.JSONHandler, logfmt (the default) for slog.TextHandler. Both emit the
+// same ts/level/msg/caller field set, renamed via renameLogAttr from slog's
+// defaults (time/level/msg/source) so the two formats stay consistent and
+// match what operators scripting against the JSON output expect.
+func newLogHandler(format string, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		AddSource:   true,
+		ReplaceAttr: renameLogAttr,
+	}
+	if format == "json" {
+		return slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.NewTextHandler(os.Stdout, opts)
+}
+
+// renameLogAttr maps slog's default keys onto the ts/level/msg/caller field
+// set: "time" becomes "ts", and the *slog.Source carried under "source" is
+// flattened to a single "file:line" string under "caller".
+func renameLogAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "ts"
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			a.Key = "caller"
+			a.Value = slog.StringValue(fmt.Sprintf("%s:%d", src.File, src.Line))
+		}
+	}
+	return a
+}
+
+// dedupeHandler collapses a run of consecutive, identical (same level and
+// message) log records: the first record of a run is emitted at once, the
+// rest are held back, and once the run ends one "(repeated N times)" summary
+// is emitted, where N counts every record in the run including the first. A
+// run therefore shows up as two lines, not one; a run of a single record
+// gets no summary. A scrape that hits the same per-repo
+// AccessDenied error on every one of a thousand repos would otherwise
+// flood the log with a thousand identical lines.
+//
+// next is guarded by the same mutex as the dedupe state, rather than a
+// separate lock, so setNext can swap the output format (see setLogFormat)
+// while Handle is concurrently reading it without a race.
+type dedupeHandler struct {
+	mu   sync.Mutex
+	next slog.Handler
+
+	sig   string
+	count int
+	last  slog.Record
+}
+
+func (h *dedupeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.getNext().Enabled(ctx, level)
+}
+
+func (h *dedupeHandler) Handle(ctx context.Context, r slog.Record) error {
+	sig := r.Level.String() + "|" + r.Message
+
+	h.mu.Lock()
+	next := h.next
+	if sig == h.sig {
+		h.count++
+		h.last = r
+		h.mu.Unlock()
+		return nil
+	}
+
+	prevCount, prevRecord := h.count, h.last
+	h.sig = sig
+	h.count = 1
+	h.last = r
+	h.mu.Unlock()
+
+	if prevCount > 1 {
+		if err := next.Handle(ctx, dedupeSummary(prevRecord, prevCount)); err != nil {
+			return err
+		}
+	}
+
+	return next.Handle(ctx, r)
+}
+
+func (h *dedupeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &dedupeHandler{next: h.getNext().WithAttrs(attrs)}
+}
+
+func (h *dedupeHandler) WithGroup(name string) slog.Handler {
+	return &dedupeHandler{next: h.getNext().WithGroup(name)}
+}
+
+// Flush emits a pending "repeated N times" summary immediately instead of
+// waiting for a differing log line to trigger it. Call this at a natural
+// boundary (e.g. the end of a scrape) so a run of duplicates at the very
+// end of a batch isn't lost.
+func (h *dedupeHandler) Flush(ctx context.Context) {
+	h.mu.Lock()
+	next := h.next
+	prevCount, prevRecord := h.count, h.last
+	h.sig = ""
+	h.count = 0
+	h.mu.Unlock()
+
+	if prevCount > 1 {
+		next.Handle(ctx, dedupeSummary(prevRecord, prevCount))
+	}
+}
+
+// getNext returns the handler currently wrapped, synchronized against a
+// concurrent setNext.
+func (h *dedupeHandler) getNext() slog.Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.next
+}
+
+// setNext atomically replaces the wrapped handler, e.g. to switch between
+// logfmt and JSON output at runtime without losing in-flight dedupe state.
+func (h *dedupeHandler) setNext(next slog.Handler) {
+	h.mu.Lock()
+	h.next = next
+	h.mu.Unlock()
+}
+
+func dedupeSummary(r slog.Record, count int) slog.Record {
+	summary := slog.NewRecord(r.Time, r.Level, fmt.Sprintf("%s (repeated %d times)", r.Message, count), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		summary.AddAttrs(a)
+		return true
+	})
+	return summary
+}
+
+// loggingResponseWriter captures the status code written by a handler so
+// withRequestLogging can log it after the fact.
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *loggingResponseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// withRequestLogging wraps an http.Handler so every request emits one
+// structured log line with method, path, status, duration_ms, and remote.
+func withRequestLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lrw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(lrw, r)
+
+		log.Info("http request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", lrw.status,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"remote", r.RemoteAddr,
+		)
+	})
+}
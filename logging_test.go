@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDedupeHandlerCollapsesRepeats(t *testing.T) {
+	var buf bytes.Buffer
+	dedupe := &dedupeHandler{next: slog.NewTextHandler(&buf, nil)}
+	logger := slog.New(dedupe)
+
+	for i := 0; i < 3; i++ {
+		logger.Error("AccessDenied")
+	}
+	logger.Info("done")
+
+	output := buf.String()
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines (first occurrence, summary, done), got %d:\n%s", len(lines), output)
+	}
+	if !strings.Contains(lines[1], "repeated 3 times") {
+		t.Errorf("Expected second line to summarize all 3 occurrences, got %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "done") {
+		t.Errorf("Expected third line to be the new message, got %q", lines[2])
+	}
+}
+
+func TestDedupeHandlerFlush(t *testing.T) {
+	var buf bytes.Buffer
+	dedupe := &dedupeHandler{next: slog.NewTextHandler(&buf, nil)}
+	logger := slog.New(dedupe)
+
+	logger.Error("AccessDenied")
+	logger.Error("AccessDenied")
+	dedupe.Flush(context.Background())
+
+	output := buf.String()
+	if !strings.Contains(output, "repeated 2 times") {
+		t.Errorf("Expected Flush to emit a repeat summary, got %q", output)
+	}
+}
+
+func TestRenameLogAttrJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: renameLogAttr}))
+	handler.Info("hello", "key", "value")
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a valid JSON line, got error %v for %q", err, buf.String())
+	}
+
+	for _, field := range []string{"ts", "level", "msg"} {
+		if _, ok := line[field]; !ok {
+			t.Errorf("Expected field %q in JSON log line, got %v", field, line)
+		}
+	}
+	if line["msg"] != "hello" {
+		t.Errorf("Expected msg %q, got %v", "hello", line["msg"])
+	}
+}
+
+func TestSetLogFormatSwapsOutput(t *testing.T) {
+	var buf bytes.Buffer
+	dedupe := &dedupeHandler{next: slog.NewTextHandler(&buf, nil)}
+	origDedupe, origLog := logDedupe, log
+	logDedupe = dedupe
+	log = slog.New(dedupe)
+	defer func() { logDedupe, log = origDedupe, origLog }()
+
+	// setLogFormat builds its replacement handler on os.Stdout, so point
+	// os.Stdout at a temp file to capture what is logged after the swap.
+	stdout, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	origStdout := os.Stdout
+	os.Stdout = stdout
+	defer func() {
+		os.Stdout = origStdout
+		stdout.Close()
+	}()
+
+	log.Info("before")
+	setLogFormat("json")
+	log.Info("after")
+
+	after, err := os.ReadFile(stdout.Name())
+	if err != nil {
+		t.Fatalf("Failed to read captured stdout: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()+string(after)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d: %v", len(lines), lines)
+	}
+	if strings.HasPrefix(lines[0], "{") {
+		t.Errorf("Expected first line to still be text format, got %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "{") {
+		t.Errorf("Expected second line to be JSON after setLogFormat, got %q", lines[1])
+	}
+}
+
+func TestSetLogFormatConcurrentWithLogging(t *testing.T) {
+	var buf bytes.Buffer
+	dedupe := &dedupeHandler{next: slog.NewTextHandler(&buf, nil)}
+	origDedupe, origLog := logDedupe, log
+	logDedupe = dedupe
+	log = slog.New(dedupe)
+	defer func() { logDedupe, log = origDedupe, origLog }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			log.Info("concurrent")
+		}()
+		go func() {
+			defer wg.Done()
+			setLogFormat("json")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithRequestLoggingCapturesStatus(t *testing.T) {
+	var buf bytes.Buffer
+	originalLog := log
+	log = slog.New(slog.NewTextHandler(&buf, nil))
+	defer func() { log = originalLog }()
+
+	handler := withRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	req := httptest.NewRequest("GET", "/teapot", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	output := buf.String()
+	if !strings.Contains(output, "status=418") {
+		t.Errorf("Expected logged status=418, got %q", output)
+	}
+	if !strings.Contains(output, "path=/teapot") {
+		t.Errorf("Expected logged path=/teapot, got %q", output)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	errInvalidBody  = errors.New("malformed request body")
+	errMissingField = errors.New("level or format is required")
+)
+
+// logLevelRequest is both the PUT/POST request body shape and what
+// parseLogLevelRequest normalizes query params and form bodies into. Either
+// field may be empty, meaning "leave that setting alone".
+type logLevelRequest struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// logLevelResponse is the JSON shape returned by GET /admin/loglevel and
+// after a successful PUT/POST.
+type logLevelResponse struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type logLevelError struct {
+	Error string `json:"error"`
+}
+
+// logLevelHandler serves GET /admin/loglevel (current level and format, as
+// JSON) and PUT/POST /admin/loglevel (change either at runtime), so an
+// operator can raise verbosity or switch to JSON output to chase down a
+// problem without restarting the exporter. logLevel is a *slog.LevelVar and
+// logDedupe's handler is swapped under a mutex, so both are safe to change
+// concurrently with in-flight requests logging through them.
+func logLevelHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeLogLevelJSON(w, http.StatusOK, currentLogLevelResponse())
+
+	case http.MethodPut, http.MethodPost:
+		req, err := parseLogLevelRequest(r)
+		if err != nil {
+			writeLogLevelError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if req.Level != "" {
+			level, ok := parseLogLevel(req.Level)
+			if !ok {
+				writeLogLevelError(w, http.StatusBadRequest, "unknown log level: "+req.Level)
+				return
+			}
+			logLevel.Set(level)
+			log.Info("Log level changed", "log_level", level.String())
+		}
+
+		if req.Format != "" {
+			if !validLogFormat(req.Format) {
+				writeLogLevelError(w, http.StatusBadRequest, "unknown log format: "+req.Format)
+				return
+			}
+			setLogFormat(req.Format)
+			log.Info("Log format changed", "format", logFormatName(req.Format))
+		}
+
+		writeLogLevelJSON(w, http.StatusOK, currentLogLevelResponse())
+
+	default:
+		writeLogLevelError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func currentLogLevelResponse() logLevelResponse {
+	return logLevelResponse{
+		Level:  logLevel.Level().String(),
+		Format: logFormatName(getLogFormat()),
+	}
+}
+
+// parseLogLevelRequest extracts the requested level/format from, in order of
+// precedence: query params (e.g. PUT /admin/loglevel?format=json), an
+// application/x-www-form-urlencoded body (level=debug&format=json), or an
+// application/json body ({"level":"debug","format":"json"}).
+func parseLogLevelRequest(r *http.Request) (logLevelRequest, error) {
+	req := logLevelRequest{
+		Level:  r.URL.Query().Get("level"),
+		Format: r.URL.Query().Get("format"),
+	}
+	if req.Level != "" || req.Format != "" {
+		return req, nil
+	}
+
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); err != nil {
+			return req, errInvalidBody
+		}
+		req.Level = r.PostForm.Get("level")
+		req.Format = r.PostForm.Get("format")
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, errInvalidBody
+	}
+
+	if req.Level == "" && req.Format == "" {
+		return req, errMissingField
+	}
+	return req, nil
+}
+
+func writeLogLevelJSON(w http.ResponseWriter, status int, resp logLevelResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func writeLogLevelError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(logLevelError{Error: msg})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withTestLogDedupe points the package-level logDedupe/log at a throwaway
+// handler for the duration of a test and restores them, and the recorded log
+// format, afterwards, so a test's setLogFormat call swaps the throwaway
+// handler instead of leaving the shared package default changed.
+func withTestLogDedupe(t *testing.T) {
+	t.Helper()
+	origDedupe, origLog, origFormat := logDedupe, log, getLogFormat()
+	logDedupe = &dedupeHandler{next: slog.NewTextHandler(io.Discard, nil)}
+	log = slog.New(logDedupe)
+	t.Cleanup(func() {
+		logFormatMu.Lock()
+		currentLogFormat = origFormat
+		logFormatMu.Unlock()
+		logDedupe, log = origDedupe, origLog
+	})
+}
+
+func TestLogLevelHandlerGet(t *testing.T) {
+	orig := logLevel.Level()
+	defer logLevel.Set(orig)
+	logLevel.Set(slog.LevelWarn)
+
+	req := httptest.NewRequest("GET", "/admin/loglevel", nil)
+	w := httptest.NewRecorder()
+	logLevelHandler(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp logLevelResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Level != "WARN" {
+		t.Errorf("Expected level WARN, got %s", resp.Level)
+	}
+}
+
+func TestLogLevelHandlerSet(t *testing.T) {
+	orig := logLevel.Level()
+	defer logLevel.Set(orig)
+
+	tests := []struct {
+		name           string
+		method         string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedLevel  slog.Level
+	}{
+		{
+			name:           "JSON body PUT",
+			method:         "PUT",
+			contentType:    "application/json",
+			body:           `{"level":"debug"}`,
+			expectedStatus: 200,
+			expectedLevel:  slog.LevelDebug,
+		},
+		{
+			name:           "form body POST",
+			method:         "POST",
+			contentType:    "application/x-www-form-urlencoded",
+			body:           "level=error",
+			expectedStatus: 200,
+			expectedLevel:  slog.LevelError,
+		},
+		{
+			name:           "unknown level rejected",
+			method:         "PUT",
+			contentType:    "application/json",
+			body:           `{"level":"verbose"}`,
+			expectedStatus: 400,
+		},
+		{
+			name:           "malformed JSON rejected",
+			method:         "PUT",
+			contentType:    "application/json",
+			body:           `{"level":`,
+			expectedStatus: 400,
+		},
+		{
+			name:           "missing level field rejected",
+			method:         "PUT",
+			contentType:    "application/json",
+			body:           `{}`,
+			expectedStatus: 400,
+		},
+		{
+			name:           "invalid method rejected",
+			method:         "DELETE",
+			contentType:    "application/json",
+			body:           `{"level":"debug"}`,
+			expectedStatus: 405,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logLevel.Set(slog.LevelInfo)
+
+			req := httptest.NewRequest(tt.method, "/admin/loglevel", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			logLevelHandler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus == 200 && logLevel.Level() != tt.expectedLevel {
+				t.Errorf("Expected level %v, got %v", tt.expectedLevel, logLevel.Level())
+			}
+		})
+	}
+}
+
+func TestLogLevelHandlerSetFormat(t *testing.T) {
+	withTestLogDedupe(t)
+
+	req := httptest.NewRequest("PUT", "/admin/loglevel", strings.NewReader(`{"format":"json"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	logLevelHandler(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp logLevelResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Format != "json" {
+		t.Errorf("Expected format json, got %s", resp.Format)
+	}
+	if getLogFormat() != "json" {
+		t.Errorf("Expected getLogFormat() to report json, got %s", getLogFormat())
+	}
+}
+
+func TestLogLevelHandlerFormatQueryParam(t *testing.T) {
+	withTestLogDedupe(t)
+
+	req := httptest.NewRequest("PUT", "/admin/loglevel?format=text", nil)
+	w := httptest.NewRecorder()
+	logLevelHandler(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if getLogFormat() != "text" {
+		t.Errorf("Expected getLogFormat() to report text, got %s", getLogFormat())
+	}
+}
+
+func TestLogLevelHandlerUnknownFormatRejected(t *testing.T) {
+	req := httptest.NewRequest("PUT", "/admin/loglevel", strings.NewReader(`{"format":"xml"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	logLevelHandler(w, req)
+
+	if w.Code != 400 {
+		t.Errorf("Expected status 400 for unknown format, got %d", w.Code)
+	}
+}
@@ -3,19 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"runtime"
-	"strings"
+	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -24,7 +23,13 @@ const (
 )
 
 var (
-	log       = logrus.New()
+	configFile  = flag.String("config.file", "ecr_exporter.yml", "Path to the YAML file listing scrape targets (region/role per account).")
+	concurrency = flag.Int("scrape.concurrency", defaultConcurrency, "Maximum number of targets scraped in parallel.")
+	cacheTTL    = flag.Duration("cache.ttl", defaultCacheTTL, "How often the background cache refresh re-scrapes ECR.")
+)
+
+var (
+	log       = slog.Default()
 	startTime = time.Now()
 )
 
@@ -85,7 +90,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	} else {
 		w.Header().Set("Content-Type", "text/html")
 		w.WriteHeader(http.StatusOK)
-		
+
 		html := fmt.Sprintf(`
 <!DOCTYPE html>
 <html>
@@ -110,7 +115,7 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
     <h1>ECR Prometheus Exporter Health Status</h1>
     <p class="status">Status: %s</p>
     <p>Last Updated: %s</p>
-    
+
     <h2>System Metrics</h2>
     <table>
         <tr><th>Metric</th><th>Value</th></tr>
@@ -122,15 +127,15 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
         <tr><td>System Memory</td><td class="value">%.2f MB</td></tr>
         <tr><td>GC Runs</td><td class="value">%d</td></tr>
     </table>
-    
+
     <div class="refresh">
         <button onclick="refreshPage()">Refresh Now</button>
         <span style="margin-left: 20px;">Auto-refresh: 30s</span>
     </div>
-    
+
     <p style="margin-top: 30px;">
-        <a href="/">← Back to Home</a> | 
-        <a href="/metrics">View Metrics</a> | 
+        <a href="/">← Back to Home</a> |
+        <a href="/metrics">View Metrics</a> |
         <a href="/health?format=json">JSON Format</a>
     </p>
 </body>
@@ -145,91 +150,87 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 			health.Memory.SysMB,
 			health.Memory.NumGC,
 		)
-		
+
 		w.Write([]byte(html))
 	}
 }
 
-func configureLogging() {
-	// Set log format to logfmt (key=value pairs)
-	log.SetFormatter(&logrus.TextFormatter{
-		DisableColors:   true,
-		FullTimestamp:   true,
-		TimestampFormat: time.RFC3339,
-	})
-
-	// Get log level from environment variable
-	logLevelStr := strings.ToLower(os.Getenv("LOG_LEVEL"))
-	if logLevelStr == "" {
-		logLevelStr = "info" // Default to info level
+// reloadTargets reloads the config file and swaps the collector's clients
+// for freshly built ones. It's called on every SIGHUP; at startup main
+// builds the collector from the config file directly.
+func reloadTargets(collector *ECRCollector) error {
+	cfg, err := loadTargetsConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("loading targets config: %w", err)
 	}
 
-	var logLevel logrus.Level
-	switch logLevelStr {
-	case "debug":
-		logLevel = logrus.DebugLevel
-	case "info":
-		logLevel = logrus.InfoLevel
-	case "warn", "warning":
-		logLevel = logrus.WarnLevel
-	case "error":
-		logLevel = logrus.ErrorLevel
-	case "fatal":
-		logLevel = logrus.FatalLevel
-	case "panic":
-		logLevel = logrus.PanicLevel
-	default:
-		log.Warnf("Invalid LOG_LEVEL '%s', defaulting to 'info'. Valid levels: debug, info, warn, error, fatal, panic", logLevelStr)
-		logLevel = logrus.InfoLevel
+	clients, err := buildTargetClients(context.Background(), cfg)
+	if err != nil {
+		return fmt.Errorf("building target clients: %w", err)
 	}
 
-	log.SetLevel(logLevel)
-	log.WithFields(logrus.Fields{
-		"log_level": logLevel.String(),
-		"format":    "logfmt",
-	}).Info("Logging configured")
+	collector.SetClients(clients)
+	log.Info("Loaded targets", "count", len(clients), "config_file", *configFile)
+	return nil
+}
+
+// watchForReload rebuilds the collector's clients from configFile whenever
+// the process receives SIGHUP, following the reload-by-signal convention
+// used by statsd_exporter and most other Prometheus exporters.
+func watchForReload(collector *ECRCollector) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	for range sighup {
+		log.Info("Received SIGHUP, reloading target config")
+		if err := reloadTargets(collector); err != nil {
+			log.Error("Failed to reload target config", "error", err)
+		}
+	}
 }
 
 func main() {
+	flag.Parse()
 	configureLogging()
 	log.Info("Starting ECR Prometheus Exporter")
 
-	// Load AWS configuration
-	log.Info("Loading AWS configuration...")
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+	log.Info("Loading targets", "config_file", *configFile)
+	cfg, err := loadTargetsConfig(*configFile)
 	if err != nil {
-		log.Fatalf("Failed to load AWS config: %v", err)
+		log.Error("Failed to load targets config", "error", err)
+		os.Exit(1)
 	}
-	log.Info("AWS configuration loaded successfully")
-
-	// Create ECR client
-	log.Info("Creating ECR client...")
-	ecrClient := ecr.NewFromConfig(cfg)
-
-	// Test AWS connectivity
-	log.Info("Testing AWS connectivity...")
-	testCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-	
-	_, err = ecrClient.DescribeRepositories(testCtx, &ecr.DescribeRepositoriesInput{
-		MaxResults: aws.Int32(1),
-	})
+
+	filter, err := compileRepoFilter()
 	if err != nil {
-		log.Errorf("AWS connectivity test failed: %v", err)
-		log.Info("Continuing anyway, metrics collection will show errors...")
-	} else {
-		log.Info("AWS connectivity test successful")
+		log.Error("Failed to compile repository filter", "error", err)
+		os.Exit(1)
+	}
+
+	log.Info("Building per-target ECR clients...")
+	collector, err := NewECRCollector(context.Background(), cfg, *concurrency, filter)
+	if err != nil {
+		log.Error("Failed to build ECR collector", "error", err)
+		os.Exit(1)
 	}
 
-	// Create and register the collector
+	go watchForReload(collector)
+
+	log.Info("Starting background cache refresh loop", "ttl", cacheTTL.String())
+	go collector.runCacheRefreshLoop(*cacheTTL)
+
 	log.Info("Registering Prometheus collector...")
-	collector := NewECRCollector(ecrClient)
 	prometheus.MustRegister(collector)
 
 	// Setup HTTP server
-	http.Handle("/metrics", promhttp.Handler())
-	http.HandleFunc("/health", healthHandler)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/metrics", withRequestLogging(promhttp.Handler()))
+	http.Handle("/probe", withRequestLogging(probeHandler(collector)))
+	http.Handle("/-/refresh", withRequestLogging(refreshHandler(collector)))
+	http.Handle("/health", withRequestLogging(http.HandlerFunc(healthHandler)))
+	http.Handle("/healthz", withRequestLogging(http.HandlerFunc(healthzHandler)))
+	http.Handle("/readyz", withRequestLogging(readyzHandler(collector.Checkables)))
+	http.Handle("/admin/loglevel", withRequestLogging(http.HandlerFunc(logLevelHandler)))
+	http.Handle("/", withRequestLogging(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`<html>
 			<head>
 				<title>ECR Exporter</title>
@@ -242,12 +243,13 @@ func main() {
 			<body>
 				<h1>ECR Prometheus Exporter</h1>
 				<p>Monitor your AWS ECR repositories with Prometheus metrics</p>
-				
+
 				<h2>Available Endpoints:</h2>
 				<a href="/metrics" class="link">📊 Prometheus Metrics</a>
+				<a href="/probe?target=&region=" class="link">🔍 Probe a Single Repository</a>
 				<a href="/health" class="link">💚 Health Status (with system metrics)</a>
 				<a href="/health?format=json" class="link">📋 Health Status (JSON)</a>
-				
+
 				<h2>Metrics Exported:</h2>
 				<ul>
 					<li>Total ECR repositories</li>
@@ -258,10 +260,11 @@ func main() {
 				</ul>
 			</body>
 			</html>`))
-	})
+	})))
 
-	log.Infof("Server starting on port %s", port)
+	log.Info("Server starting", "port", port)
 	if err := http.ListenAndServe(port, nil); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		log.Error("Failed to start server", "error", err)
+		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,176 @@
+package main
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// metricDescs holds every metric description the exporter can emit. It's
+// shared by the global ECRCollector (registered once, scraped by /metrics)
+// and the per-request registry built by probeHandler, so both paths stay in
+// sync on metric names, help text, and label sets.
+type metricDescs struct {
+	repoCount          *prometheus.Desc
+	imageCount         *prometheus.Desc
+	imageSizeMax       *prometheus.Desc
+	imageSizeMin       *prometheus.Desc
+	imageSizeAvg       *prometheus.Desc
+	latestPushTime     *prometheus.Desc
+	latestPullTime     *prometheus.Desc
+	scrapeErrors       *prometheus.Desc
+	scrapeDuration     *prometheus.Desc
+	targetScrapeErrors *prometheus.Desc
+	targetScrapeDur    *prometheus.Desc
+
+	cacheLastRefresh   *prometheus.Desc
+	cacheRefreshDur    *prometheus.Desc
+	cacheRefreshErrors *prometheus.Desc
+
+	imageVulnerabilities *prometheus.Desc
+	imageScanStatus      *prometheus.Desc
+	imageScanCompleted   *prometheus.Desc
+
+	aggImageCount *prometheus.Desc
+	aggImageSize  *prometheus.Desc
+}
+
+func newMetricDescs() *metricDescs {
+	return &metricDescs{
+		repoCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "repositories_total"),
+			"Total number of ECR repositories",
+			[]string{"target", "region", "account"},
+			nil,
+		),
+		imageCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "images_total"),
+			"Number of images in ECR repository",
+			[]string{"repository_name", "repository_uri", "region", "account"},
+			nil,
+		),
+		imageSizeMax: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "image_size_max_bytes"),
+			"Maximum image size in repository (bytes)",
+			[]string{"repository_name", "repository_uri", "region", "account"},
+			nil,
+		),
+		imageSizeMin: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "image_size_min_bytes"),
+			"Minimum image size in repository (bytes)",
+			[]string{"repository_name", "repository_uri", "region", "account"},
+			nil,
+		),
+		imageSizeAvg: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "image_size_avg_bytes"),
+			"Average image size in repository (bytes)",
+			[]string{"repository_name", "repository_uri", "region", "account"},
+			nil,
+		),
+		latestPushTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "latest_push_timestamp"),
+			"Timestamp of latest image push",
+			[]string{"repository_name", "repository_uri", "region", "account"},
+			nil,
+		),
+		latestPullTime: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "latest_pull_timestamp"),
+			"Timestamp of latest image pull",
+			[]string{"repository_name", "repository_uri", "region", "account"},
+			nil,
+		),
+		scrapeErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "scrape_errors_total"),
+			"Total number of scrape errors across all targets",
+			nil,
+			nil,
+		),
+		scrapeDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "scrape_duration_seconds"),
+			"Duration of the full, all-targets scrape",
+			nil,
+			nil,
+		),
+		targetScrapeErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "target_scrape_errors_total"),
+			"Number of scrape errors for a single target",
+			[]string{"target"},
+			nil,
+		),
+		targetScrapeDur: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "target_scrape_duration_seconds"),
+			"Duration of the scrape for a single target",
+			[]string{"target"},
+			nil,
+		),
+		cacheLastRefresh: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "last_refresh_timestamp_seconds"),
+			"Unix timestamp of the last cache refresh, including refreshes that hit scrape errors",
+			nil,
+			nil,
+		),
+		cacheRefreshDur: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "refresh_duration_seconds"),
+			"Duration of the last background cache refresh",
+			nil,
+			nil,
+		),
+		cacheRefreshErrors: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "cache", "refresh_errors_total"),
+			"Total number of background cache refreshes that encountered at least one scrape error",
+			nil,
+			nil,
+		),
+		imageVulnerabilities: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "image", "vulnerabilities"),
+			"Number of image scan findings for the repository's newest image, by severity",
+			[]string{"repository_name", "severity"},
+			nil,
+		),
+		imageScanStatus: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "image", "scan_status"),
+			"Scan status of a repository's newest image (value is always 1; status is a label)",
+			[]string{"repository_name", "image_digest", "status"},
+			nil,
+		),
+		imageScanCompleted: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "image", "scan_completed_timestamp"),
+			"Unix timestamp the scan for a repository's newest image completed",
+			[]string{"repository_name", "image_digest"},
+			nil,
+		),
+		aggImageCount: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "repository_image_count"),
+			"Histogram of image counts per repository, across all repositories in a target (only emitted when --metrics.per-repo=false)",
+			[]string{"target", "region", "account"},
+			nil,
+		),
+		aggImageSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "image_size_bytes"),
+			"Histogram of image sizes across all repositories in a target (only emitted when --metrics.per-repo=false)",
+			[]string{"target", "region", "account"},
+			nil,
+		),
+	}
+}
+
+// all returns every desc, in the same order Describe() sends them in.
+func (d *metricDescs) all() []*prometheus.Desc {
+	return []*prometheus.Desc{
+		d.repoCount,
+		d.imageCount,
+		d.imageSizeMax,
+		d.imageSizeMin,
+		d.imageSizeAvg,
+		d.latestPushTime,
+		d.latestPullTime,
+		d.scrapeErrors,
+		d.scrapeDuration,
+		d.targetScrapeErrors,
+		d.targetScrapeDur,
+		d.cacheLastRefresh,
+		d.cacheRefreshDur,
+		d.cacheRefreshErrors,
+		d.imageVulnerabilities,
+		d.imageScanStatus,
+		d.imageScanCompleted,
+		d.aggImageCount,
+		d.aggImageSize,
+	}
+}
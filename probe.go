@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeTimeout bounds how long a single /probe request waits on ECR before
+// giving up, independent of the 5-minute per-target cache refresh timeout.
+const probeTimeout = 30 * time.Second
+
+// collectedMetrics is a prometheus.Collector that just replays a fixed
+// slice of metrics it was handed, so collectRepositoryMetrics's output can
+// be registered on a throwaway per-request registry.
+type collectedMetrics struct {
+	descs   []*prometheus.Desc
+	metrics []prometheus.Metric
+}
+
+func (c *collectedMetrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range c.descs {
+		ch <- d
+	}
+}
+
+func (c *collectedMetrics) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c.metrics {
+		ch <- m
+	}
+}
+
+// probeHandler implements a blackbox_exporter-style multi-target probe:
+// /probe?target=<repository-name>&region=<region> collects and returns
+// metrics for exactly one repository against a fresh prometheus.Registry,
+// so Prometheus can fan out one scrape per repository via relabel_configs
+// instead of waiting on the shared, all-repos /metrics Collect().
+func probeHandler(collector *ECRCollector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repoName := r.URL.Query().Get("target")
+		region := r.URL.Query().Get("region")
+
+		if repoName == "" {
+			http.Error(w, "target parameter is required", http.StatusBadRequest)
+			return
+		}
+		if region == "" {
+			http.Error(w, "region parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		tc, err := collector.targetByRegion(region)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ecr_probe_success",
+			Help: "Whether the probe of the requested repository succeeded (1) or failed (0)",
+		})
+		probeDuration := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ecr_probe_duration_seconds",
+			Help: "Duration of the probe of the requested repository",
+		})
+
+		ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+		defer cancel()
+
+		start := time.Now()
+		ch := make(chan prometheus.Metric, 16)
+		var collected []prometheus.Metric
+		done := make(chan struct{})
+		go func() {
+			for m := range ch {
+				collected = append(collected, m)
+			}
+			close(done)
+		}()
+
+		probeErr := collectRepositoryByName(ctx, tc.client, collector.descs, collector.scanCache, collector.scanSem, repoName, tc.target.Region, tc.accountID, ch)
+		close(ch)
+		<-done
+
+		probeDuration.Set(time.Since(start).Seconds())
+		if probeErr != nil {
+			log.Error("Probe failed", "repository", repoName, "target", tc.target.Name, "error", probeErr)
+			probeSuccess.Set(0)
+		} else {
+			probeSuccess.Set(1)
+		}
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(probeSuccess, probeDuration)
+		registry.MustRegister(&collectedMetrics{descs: collector.descs.all(), metrics: collected})
+
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeHandlerValidation(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{
+			name:           "missing target",
+			url:            "/probe?region=us-east-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing region",
+			url:            "/probe?target=my-repo",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown region",
+			url:            "/probe?target=my-repo&region=us-east-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	collector := newECRCollector(nil, 0, nil)
+	handler := probeHandler(collector)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
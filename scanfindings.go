@@ -0,0 +1,182 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	collectScanFindings = flag.Bool("collect.scan-findings", true, "Collect image vulnerability counts via DescribeImageScanFindings for the newest tag in each repository.")
+	scanConcurrency     = flag.Int("scan.concurrency", 4, "Maximum number of concurrent DescribeImageScanFindings calls.")
+	scanCacheSize       = flag.Int("scan.cache-size", 10000, "Number of image digests to cache scan findings for. Findings for a digest never change once the scan completes.")
+)
+
+// scanSeverities is every severity DescribeImageScanFindings can report.
+// Metrics are emitted for all of them, zero-filled when absent, so rate()
+// and sum() queries don't silently drop a severity that had no findings.
+var scanSeverities = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL", "UNDEFINED"}
+
+// imageScanFindings is the subset of DescribeImageScanFindings we care
+// about for a single image digest.
+type imageScanFindings struct {
+	status         string
+	completedAt    time.Time
+	severityCounts map[string]int32
+}
+
+// scanFindingsCache is a bounded LRU keyed by image digest. Scan findings
+// for a given digest never change once the scan completes, since ECR
+// images are content-addressed and immutable, so a digest never needs to
+// be re-fetched.
+type scanFindingsCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type scanCacheEntry struct {
+	digest   string
+	findings *imageScanFindings
+}
+
+func newScanFindingsCache(capacity int) *scanFindingsCache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &scanFindingsCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *scanFindingsCache) get(digest string) (*imageScanFindings, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[digest]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*scanCacheEntry).findings, true
+}
+
+func (c *scanFindingsCache) put(digest string, findings *imageScanFindings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[digest]; ok {
+		c.ll.MoveToFront(el)
+		el.Value.(*scanCacheEntry).findings = findings
+		return
+	}
+
+	el := c.ll.PushFront(&scanCacheEntry{digest: digest, findings: findings})
+	c.items[digest] = el
+
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*scanCacheEntry).digest)
+		}
+	}
+}
+
+// newestImage returns the image with the most recent ImagePushedAt, since
+// scan findings are collected for the newest tag per repository rather
+// than every tag.
+func newestImage(images []types.ImageDetail) (types.ImageDetail, bool) {
+	var newest types.ImageDetail
+	var found bool
+
+	for _, image := range images {
+		if image.ImagePushedAt == nil || image.ImageDigest == nil {
+			continue
+		}
+		if !found || image.ImagePushedAt.After(*newest.ImagePushedAt) {
+			newest = image
+			found = true
+		}
+	}
+
+	return newest, found
+}
+
+// collectScanFindingsForRepo fetches (or reuses from cache) the scan
+// findings for the newest image in a repository and emits the
+// vulnerability, status, and completed-timestamp metrics for it.
+func collectScanFindingsForRepo(ctx context.Context, client *ecr.Client, descs *metricDescs, cache *scanFindingsCache, sem chan struct{}, repoName string, images []types.ImageDetail, ch chan<- prometheus.Metric, errorCount *int) {
+	image, ok := newestImage(images)
+	if !ok {
+		return
+	}
+	digest := *image.ImageDigest
+
+	findings, ok := cache.get(digest)
+	if !ok {
+		var err error
+		findings, err = fetchImageScanFindings(ctx, client, sem, repoName, digest)
+		if err != nil {
+			log.Error("Failed to get scan findings for image", "repository", repoName, "image_digest", digest, "error", err)
+			*errorCount++
+			return
+		}
+		if findings.status == string(types.ScanStatusComplete) {
+			cache.put(digest, findings)
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(descs.imageScanStatus, prometheus.GaugeValue, 1, repoName, digest, findings.status)
+
+	if !findings.completedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(descs.imageScanCompleted, prometheus.GaugeValue, float64(findings.completedAt.Unix()), repoName, digest)
+	}
+
+	for _, severity := range scanSeverities {
+		ch <- prometheus.MustNewConstMetric(descs.imageVulnerabilities, prometheus.GaugeValue, float64(findings.severityCounts[severity]), repoName, severity)
+	}
+}
+
+// fetchImageScanFindings calls DescribeImageScanFindings, bounded by sem,
+// and normalizes the result.
+func fetchImageScanFindings(ctx context.Context, client *ecr.Client, sem chan struct{}, repoName, digest string) (*imageScanFindings, error) {
+	sem <- struct{}{}
+	defer func() { <-sem }()
+
+	result, err := client.DescribeImageScanFindings(ctx, &ecr.DescribeImageScanFindingsInput{
+		RepositoryName: &repoName,
+		ImageId:        &types.ImageIdentifier{ImageDigest: &digest},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	findings := &imageScanFindings{
+		severityCounts: make(map[string]int32, len(scanSeverities)),
+	}
+
+	if result.ImageScanStatus != nil {
+		findings.status = string(result.ImageScanStatus.Status)
+	}
+
+	if result.ImageScanFindings != nil {
+		if result.ImageScanFindings.ImageScanCompletedAt != nil {
+			findings.completedAt = *result.ImageScanFindings.ImageScanCompletedAt
+		}
+		for severity, count := range result.ImageScanFindings.FindingSeverityCounts {
+			findings.severityCounts[severity] = count
+		}
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func TestNewestImage(t *testing.T) {
+	now := time.Now()
+	older := now.Add(-time.Hour)
+
+	digestOld, digestNew := "sha256:old", "sha256:new"
+	images := []types.ImageDetail{
+		{ImageDigest: &digestOld, ImagePushedAt: &older},
+		{ImageDigest: &digestNew, ImagePushedAt: &now},
+	}
+
+	newest, ok := newestImage(images)
+	if !ok {
+		t.Fatal("Expected to find a newest image")
+	}
+	if *newest.ImageDigest != digestNew {
+		t.Errorf("Expected newest digest %s, got %s", digestNew, *newest.ImageDigest)
+	}
+}
+
+func TestNewestImageNoTimestamps(t *testing.T) {
+	digest := "sha256:untimed"
+	images := []types.ImageDetail{{ImageDigest: &digest}}
+
+	_, ok := newestImage(images)
+	if ok {
+		t.Error("Expected no newest image when no timestamps are present")
+	}
+}
+
+func TestScanFindingsCacheGetPut(t *testing.T) {
+	cache := newScanFindingsCache(2)
+
+	if _, ok := cache.get("a"); ok {
+		t.Error("Expected cache miss on empty cache")
+	}
+
+	findingsA := &imageScanFindings{status: "COMPLETE"}
+	cache.put("a", findingsA)
+
+	got, ok := cache.get("a")
+	if !ok || got != findingsA {
+		t.Error("Expected cache hit for key 'a'")
+	}
+}
+
+func TestScanFindingsCacheEvictsOldest(t *testing.T) {
+	cache := newScanFindingsCache(2)
+
+	cache.put("a", &imageScanFindings{status: "A"})
+	cache.put("b", &imageScanFindings{status: "B"})
+	// Touch "a" so "b" becomes the least recently used entry.
+	cache.get("a")
+	cache.put("c", &imageScanFindings{status: "C"})
+
+	if _, ok := cache.get("b"); ok {
+		t.Error("Expected 'b' to have been evicted as the least recently used entry")
+	}
+	if _, ok := cache.get("a"); !ok {
+		t.Error("Expected 'a' to still be cached")
+	}
+	if _, ok := cache.get("c"); !ok {
+		t.Error("Expected 'c' to be cached")
+	}
+}